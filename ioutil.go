@@ -0,0 +1,33 @@
+package lvm
+
+import (
+	"io"
+	"sync"
+)
+
+// asReaderAt returns rs as an io.ReaderAt: rs itself if it already
+// implements the interface (as *os.File does), or an adapter that
+// serializes ReadAt calls through Seek+Read otherwise.
+func asReaderAt(rs io.ReadSeeker) io.ReaderAt {
+	if ra, ok := rs.(io.ReaderAt); ok {
+		return ra
+	}
+	return &readSeekerReaderAt{rs: rs}
+}
+
+// readSeekerReaderAt adapts an io.ReadSeeker that does not implement
+// io.ReaderAt into one, by serializing access with a mutex.
+type readSeekerReaderAt struct {
+	mu sync.Mutex
+	rs io.ReadSeeker
+}
+
+func (r *readSeekerReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.rs.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.ReadFull(r.rs, p)
+}
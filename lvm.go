@@ -2,7 +2,11 @@ package lvm
 
 import (
 	"encoding/binary"
+	"hash/crc32"
 	"io"
+	"os"
+	"sort"
+	"strings"
 
 	"github.com/alecthomas/participle/v2"
 	"github.com/alecthomas/participle/v2/lexer"
@@ -29,7 +33,27 @@ func Check(rs io.ReadSeeker) (bool, error) {
 	return string(buf[:8]) == "LABELONE", nil
 }
 
-func Volume(rs io.ReadSeeker) (*types.Volume, error) {
+// Open opens the named file as an LVM2 physical volume image. The
+// returned Volume owns the underlying file and it must be released with
+// Close.
+func Open(name string) (*types.Volume, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to open %s: %w", name, err)
+	}
+	v, err := NewVolume(f)
+	if err != nil {
+		f.Close()
+		return nil, xerrors.Errorf("failed to create volume from %s: %w", name, err)
+	}
+	v.Closer = f
+	return v, nil
+}
+
+// NewVolume parses a single physical volume's label, header, and metadata
+// areas out of rs. Unlike Open, the caller retains ownership of rs and
+// Volume.Close on the result is a no-op.
+func NewVolume(rs io.ReadSeeker) (*types.Volume, error) {
 	rs.Seek(SectorSize, io.SeekStart)
 	vlh, err := NewPhysicalVolumeLabelHeader(rs)
 	if err != nil {
@@ -39,9 +63,11 @@ func Volume(rs io.ReadSeeker) (*types.Volume, error) {
 	if err != nil {
 		return nil, xerrors.Errorf("failed to create physical volume header: %w", err)
 	}
-	var v *types.Volume
-	v.LabelHeader = vlh
-	v.Header = vh
+	v := &types.Volume{
+		LabelHeader: vlh,
+		Header:      vh,
+		Reader:      asReaderAt(rs),
+	}
 
 	for _, descriptor := range v.Header.MetaDataAreaDescriptor {
 		m, err := parseMetadataArea(rs, descriptor)
@@ -77,29 +103,125 @@ func NewPhysicalVolumeHeader(r io.Reader) (types.PhysicalVolumeHeader, error) {
 	return h, nil
 }
 
+// parseMetadataArea reads a metadata area's header and every valid,
+// committed metadata copy out of its circular buffer (the ring that
+// `vgcfgbackup`/`pvcreate` maintain). Each raw_locn descriptor is
+// CRC32-verified before its text is parsed; descriptors with a zero size,
+// the IGNORED flag, or a bad checksum are skipped rather than failing the
+// whole read. The surviving copies are returned newest (highest seqno)
+// first.
 func parseMetadataArea(r io.ReadSeeker, descriptor types.DataAreaDescriptor) (types.MetadataArea, error) {
-	_, err := r.Seek(descriptor.DataAreaOffset, io.SeekStart)
-	if err != nil {
+	if _, err := r.Seek(descriptor.DataAreaOffset, io.SeekStart); err != nil {
 		return types.MetadataArea{}, xerrors.Errorf("failed to seek to metadata area: %w", err)
 	}
-	var h types.MetadataArea
-	if err := binary.Read(r, binary.LittleEndian, &h.Header); err != nil {
+	var h types.MetadataAreaHeader
+	if err := binary.Read(r, binary.LittleEndian, &h); err != nil {
 		return types.MetadataArea{}, xerrors.Errorf("failed to read metadata area header: %w", err)
 	}
 
-	for _, d := range h.Header.RawLocationDescriptors {
-		if d.DataAreaSize == 0 {
+	locations, err := parseRawLocationDescriptors(r)
+	if err != nil {
+		return types.MetadataArea{}, xerrors.Errorf("failed to parse raw location descriptors: %w", err)
+	}
+
+	mda := types.MetadataArea{Header: h, RawLocationDescriptors: locations}
+	areaStart := descriptor.DataAreaOffset
+
+	for _, loc := range locations {
+		if loc.DataAreaSize == 0 || loc.Flags&types.RawLocationFlagIgnored != 0 {
+			continue
+		}
+		raw, err := readRingBuffer(r, areaStart, h.Size, loc.DataAreaOffset, loc.DataAreaSize)
+		if err != nil {
+			return types.MetadataArea{}, xerrors.Errorf("failed to read metadata copy at offset %d: %w", loc.DataAreaOffset, err)
+		}
+		if crc32LVM(raw) != loc.Checksum {
 			continue
 		}
-		offset := h.Header.MetadataAreaOffset + d.DataAreaOffset
-		r.Seek(offset, io.SeekStart)
-		h.Metadata, err = parseMetadata(io.LimitReader(r, d.DataAreaSize-1))
+
+		text := strings.TrimRight(string(raw), "\x00")
+		m, err := parseMetadata(strings.NewReader(text))
 		if err != nil {
-			return types.MetadataArea{}, xerrors.Errorf("failed to parse metadata: %w", err)
+			continue
 		}
+
+		mda.Copies = append(mda.Copies, types.MetadataCopy{
+			Offset:   loc.DataAreaOffset,
+			Size:     loc.DataAreaSize,
+			Checksum: loc.Checksum,
+			Seqno:    m.Seqno,
+			Raw:      text,
+			Metadata: m,
+		})
 	}
+	sort.Slice(mda.Copies, func(i, j int) bool { return mda.Copies[i].Seqno > mda.Copies[j].Seqno })
 
-	return h, nil
+	return mda, nil
+}
+
+// parseRawLocationDescriptors reads the NULL-terminated list of raw_locn
+// entries following a metadata area header.
+func parseRawLocationDescriptors(r io.Reader) ([]types.RawLocationDescriptor, error) {
+	var ds []types.RawLocationDescriptor
+	for {
+		var d types.RawLocationDescriptor
+		if err := binary.Read(r, binary.LittleEndian, &d); err != nil {
+			return nil, xerrors.Errorf("failed to read raw location descriptor: %w", err)
+		}
+		if d.DataAreaOffset == 0 && d.DataAreaSize == 0 {
+			break
+		}
+		ds = append(ds, d)
+	}
+	return ds, nil
+}
+
+// readRingBuffer reads size bytes starting at offset — an absolute offset
+// from areaStart, exactly as raw_locn records it, with the first copy
+// conventionally sitting right after the SectorSize-byte mda_header — out
+// of a metadata area spanning ringSize bytes (mda_header.size, the whole
+// area including that header) from areaStart. It stitches two reads
+// together when the range wraps past the end of the area, since wrapped
+// data resumes at areaStart+SectorSize, right after the header, not at
+// areaStart itself.
+func readRingBuffer(r io.ReadSeeker, areaStart, ringSize, offset, size int64) ([]byte, error) {
+	buf := make([]byte, size)
+
+	if offset+size <= ringSize {
+		if _, err := r.Seek(areaStart+offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return buf, nil
+	}
+
+	first := ringSize - offset
+	if _, err := r.Seek(areaStart+offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(r, buf[:first]); err != nil {
+		return nil, err
+	}
+	if _, err := r.Seek(areaStart+SectorSize, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(r, buf[first:]); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// lvmCRCInitial is the seed LVM2 uses for the metadata checksum: a
+// standard reflected CRC32 (IEEE 802.3 polynomial) computed with this
+// initial value instead of the usual 0xffffffff, and no final XOR.
+const lvmCRCInitial uint32 = 0xf597a6cf
+
+func crc32LVM(data []byte) uint32 {
+	// crc32.Update inverts its seed on entry and its result on exit; undo
+	// both so the register really starts at lvmCRCInitial with no final XOR.
+	return ^crc32.Update(^lvmCRCInitial, crc32.IEEETable, data)
 }
 
 var (
@@ -150,4 +272,4 @@ func NewPhysicalVolumeLabelHeader(r io.Reader) (types.PhysicalVolumeLabelHeader,
 		return types.PhysicalVolumeLabelHeader{}, xerrors.Errorf("failed to read physical volume label header: %w", err)
 	}
 	return h, nil
-}
\ No newline at end of file
+}
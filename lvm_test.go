@@ -0,0 +1,315 @@
+package lvm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/masahiro331/go-lvm/types"
+)
+
+// marshalParse runs m through Marshal and back through this package's own
+// parser, the same round trip a metadata area write/read cycle performs.
+func marshalParse(t *testing.T, m types.MainSection) types.MainSection {
+	t.Helper()
+	text, err := m.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	got, err := parseMetadata(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("parseMetadata: %v\n%s", err, text)
+	}
+	return got
+}
+
+// TestMarshalParseRoundTripSegments checks that every segment type Marshal
+// knows how to write comes back out of parseMetadata unchanged, one
+// logical volume per segment type.
+func TestMarshalParseRoundTripSegments(t *testing.T) {
+	tests := []struct {
+		name string
+		seg  types.Segment
+	}{
+		{
+			name: "striped",
+			seg: types.Segment{
+				ExtentCount: 100,
+				Type:        "striped",
+				StripeCount: 2,
+				StripeSize:  8,
+				Stripes: []types.Stripe{
+					{PVName: "pv0", StartExtent: 0},
+					{PVName: "pv1", StartExtent: 50},
+				},
+			},
+		},
+		{
+			name: "linear",
+			seg: types.Segment{
+				ExtentCount: 100,
+				Type:        "linear",
+				StripeCount: 1,
+				Stripes:     []types.Stripe{{PVName: "pv0", StartExtent: 0}},
+			},
+		},
+		{
+			name: "mirror",
+			seg: types.Segment{
+				ExtentCount: 100,
+				Type:        "mirror",
+				StripeCount: 2,
+				Stripes: []types.Stripe{
+					{PVName: "pv0", StartExtent: 0},
+					{PVName: "pv1", StartExtent: 0},
+				},
+			},
+		},
+		{
+			name: "raid1",
+			seg: types.Segment{
+				ExtentCount: 100,
+				Type:        "raid1",
+				StripeCount: 2,
+				Stripes: []types.Stripe{
+					{PVName: "pv0", StartExtent: 0},
+					{PVName: "pv1", StartExtent: 0},
+				},
+			},
+		},
+		{
+			name: "thin-pool",
+			seg: types.Segment{
+				ExtentCount:   100,
+				Type:          "thin-pool",
+				PoolMetadata:  "lvol0_pmspare",
+				PoolData:      "lvol0_tdata",
+				ChunkSize:     128,
+				ZeroNewBlocks: 1,
+			},
+		},
+		{
+			name: "thin",
+			seg: types.Segment{
+				ExtentCount: 100,
+				Type:        "thin",
+				ThinPool:    "pool0",
+				DeviceID:    3,
+			},
+		},
+		{
+			name: "snapshot",
+			seg: types.Segment{
+				ExtentCount: 100,
+				Type:        "snapshot",
+				Origin:      "origin_lv",
+				COWStore:    "cow_lv",
+			},
+		},
+		{
+			name: "snapshot-origin",
+			seg: types.Segment{
+				ExtentCount: 100,
+				Type:        "snapshot-origin",
+				Origin:      "origin_lv",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := types.MainSection{
+				Name:       "vg0",
+				ID:         "vg-uuid",
+				Seqno:      1,
+				ExtentSize: 8192,
+				PhysicalVolumes: []types.PhysicalVolume{
+					{Name: "pv0", ID: "pv0-uuid", DevSize: 1000, PEStart: 2048, PECount: 100},
+					{Name: "pv1", ID: "pv1-uuid", DevSize: 1000, PEStart: 2048, PECount: 100},
+				},
+				LogicalVolumes: []types.LogicalVolume{
+					{Name: "lv0", ID: "lv0-uuid", Segments: []types.Segment{tt.seg}},
+				},
+			}
+
+			got := marshalParse(t, m)
+			if len(got.LogicalVolumes) != 1 || len(got.LogicalVolumes[0].Segments) != 1 {
+				t.Fatalf("got %d logical volumes, want 1 with 1 segment: %+v", len(got.LogicalVolumes), got)
+			}
+			if gotSeg := got.LogicalVolumes[0].Segments[0]; !reflect.DeepEqual(gotSeg, tt.seg) {
+				t.Errorf("segment round trip mismatch:\n got  %+v\n want %+v", gotSeg, tt.seg)
+			}
+		})
+	}
+}
+
+// TestRingBufferRoundTrip writes data that wraps around the end of a ring
+// buffer and checks it reads back byte-for-byte identical.
+func TestRingBufferRoundTrip(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "ring")
+	if err != nil {
+		t.Fatalf("CreateTemp: %v", err)
+	}
+	defer f.Close()
+
+	const areaStart = 0
+	const dataSize = 64
+	const ringSize = SectorSize + dataSize // mda_header.Size: header + usable ring region
+	if err := f.Truncate(areaStart + ringSize); err != nil {
+		t.Fatalf("Truncate: %v", err)
+	}
+
+	data := make([]byte, 20)
+	for i := range data {
+		data[i] = byte(i + 1)
+	}
+	// Offset chosen so the write wraps: 20 bytes starting 10 bytes from the
+	// end of the area spill 10 bytes over, which must wrap back to byte
+	// SectorSize (right after the header), not byte 0.
+	const offset = ringSize - 10
+
+	if err := writeRingBuffer(f, areaStart, ringSize, offset, data); err != nil {
+		t.Fatalf("writeRingBuffer: %v", err)
+	}
+
+	got, err := readRingBuffer(f, areaStart, ringSize, offset, int64(len(data)))
+	if err != nil {
+		t.Fatalf("readRingBuffer: %v", err)
+	}
+	if string(got) != string(data) {
+		t.Errorf("ring buffer round trip mismatch: got %v, want %v", got, data)
+	}
+
+	// The wrapped 10 bytes must have landed at SectorSize, not at 0: the
+	// header region [0, SectorSize) must still be all zero after the
+	// wrapping write.
+	if _, err := f.Seek(areaStart, io.SeekStart); err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	untouched := make([]byte, SectorSize)
+	if _, err := io.ReadFull(f, untouched); err != nil {
+		t.Fatalf("ReadFull: %v", err)
+	}
+	for i, b := range untouched {
+		if b != 0 {
+			t.Fatalf("byte %d of the header region was overwritten by the wrapped write: %v", i, untouched)
+		}
+	}
+}
+
+// buildMetadataAreaImage lays out a spec-accurate metadata area: a fixed
+// mda_header, the raw_locn list that follows it (NULL-terminated), and
+// each copy's bytes placed at its raw_locn.DataAreaOffset — an offset
+// absolute from the area's own start, wrapping past the end of the area
+// back around to byte SectorSize (right after the header) exactly as
+// readRingBuffer/writeRingBuffer do.
+func buildMetadataAreaImage(t *testing.T, areaSize int64, header types.MetadataAreaHeader, locs []types.RawLocationDescriptor, copies [][]byte) []byte {
+	t.Helper()
+	area := make([]byte, areaSize)
+
+	var hbuf bytes.Buffer
+	if err := binary.Write(&hbuf, binary.LittleEndian, header); err != nil {
+		t.Fatalf("encode header: %v", err)
+	}
+	copy(area, hbuf.Bytes())
+
+	off := int64(hbuf.Len())
+	for _, loc := range locs {
+		var lbuf bytes.Buffer
+		if err := binary.Write(&lbuf, binary.LittleEndian, loc); err != nil {
+			t.Fatalf("encode raw_locn: %v", err)
+		}
+		copy(area[off:], lbuf.Bytes())
+		off += int64(lbuf.Len())
+	}
+	var term bytes.Buffer
+	binary.Write(&term, binary.LittleEndian, types.RawLocationDescriptor{})
+	copy(area[off:], term.Bytes())
+
+	for i, loc := range locs {
+		data := copies[i]
+		if loc.DataAreaOffset+int64(len(data)) <= areaSize {
+			copy(area[loc.DataAreaOffset:], data)
+			continue
+		}
+		first := areaSize - loc.DataAreaOffset
+		copy(area[loc.DataAreaOffset:], data[:first])
+		copy(area[SectorSize:], data[first:])
+	}
+
+	return area
+}
+
+// TestParseMetadataAreaOffsets builds a metadata area byte-for-byte the
+// way pvcreate/vgcfgbackup lay one out on disk (raw_locn offsets absolute
+// from the area start, first copy right after the SectorSize-byte
+// mda_header) and checks parseMetadataArea recovers the metadata both
+// when a copy sits entirely within the area and when it wraps past the
+// end of the area back to just after the header.
+func TestParseMetadataAreaOffsets(t *testing.T) {
+	text := "vg0 {\n\tid = \"vg-uuid\"\n\tseqno = 7\n}\n"
+	raw := append([]byte(text), 0)
+
+	// slack is how much usable ring room sits beyond one copy's worth of
+	// bytes; overshoot is how far the wrapping case's copy is made to run
+	// past the end of the area. Both are chosen well clear of the header
+	// boundary so the wrapped tail can't overlap the copy's leading bytes.
+	const slack = 20
+	const overshoot = 8
+
+	tests := []struct {
+		name          string
+		areaOffset    int64
+		areaSize      int64
+		locDataOffset int64
+	}{
+		{
+			name:          "no-wrap",
+			areaOffset:    1024,
+			areaSize:      SectorSize + int64(len(raw)) + slack,
+			locDataOffset: SectorSize,
+		},
+		{
+			name:          "wraps-past-end",
+			areaOffset:    2048,
+			areaSize:      SectorSize + int64(len(raw)) + slack,
+			locDataOffset: SectorSize + slack + overshoot,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := types.RawLocationDescriptor{
+				DataAreaOffset: tt.locDataOffset,
+				DataAreaSize:   int64(len(raw)),
+				Checksum:       crc32LVM(raw),
+			}
+			header := types.MetadataAreaHeader{Version: 1, Size: tt.areaSize}
+			area := buildMetadataAreaImage(t, tt.areaSize, header, []types.RawLocationDescriptor{loc}, [][]byte{raw})
+
+			image := make([]byte, tt.areaOffset+tt.areaSize)
+			copy(image[tt.areaOffset:], area)
+
+			mda, err := parseMetadataArea(bytes.NewReader(image), types.DataAreaDescriptor{
+				DataAreaOffset: tt.areaOffset,
+				DataAreaSize:   tt.areaSize,
+			})
+			if err != nil {
+				t.Fatalf("parseMetadataArea: %v", err)
+			}
+			if len(mda.Copies) != 1 {
+				t.Fatalf("got %d copies, want 1: %+v", len(mda.Copies), mda.Copies)
+			}
+			if got := mda.Copies[0].Metadata.ID; got != "vg-uuid" {
+				t.Errorf("got ID %q, want %q", got, "vg-uuid")
+			}
+			if got := mda.Copies[0].Metadata.Seqno; got != 7 {
+				t.Errorf("got seqno %d, want 7", got)
+			}
+		})
+	}
+}
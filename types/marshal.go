@@ -0,0 +1,118 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Marshal serializes the volume group back into the LVM2 text-metadata
+// grammar parsed by Metadata/ParseMainSection — the same format
+// `vgcfgbackup` produces. The result is NOT NUL-terminated; callers
+// writing it into a metadata area's ring buffer must append that
+// themselves, as LVM counts it in the raw_locn size.
+func (m MainSection) Marshal() (string, error) {
+	if m.Name == "" {
+		return "", fmt.Errorf("lvm: volume group has no name")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s {\n", m.Name)
+	fmt.Fprintf(&b, "\tid = %s\n", quote(m.ID))
+	fmt.Fprintf(&b, "\tseqno = %d\n", m.Seqno)
+	if m.Format != "" {
+		fmt.Fprintf(&b, "\tformat = %s\n", quote(m.Format))
+	}
+	fmt.Fprintf(&b, "\tstatus = %s\n", quoteList(m.Status))
+	fmt.Fprintf(&b, "\tflags = %s\n", quoteList(m.Flags))
+	fmt.Fprintf(&b, "\textent_size = %d\n", m.ExtentSize)
+	fmt.Fprintf(&b, "\tmax_lv = %d\n", m.MaxLV)
+	fmt.Fprintf(&b, "\tmax_pv = %d\n", m.MaxPV)
+
+	b.WriteString("\n\tphysical_volumes {\n")
+	for _, pv := range m.PhysicalVolumes {
+		writePhysicalVolume(&b, pv)
+	}
+	b.WriteString("\t}\n")
+
+	b.WriteString("\n\tlogical_volumes {\n")
+	for _, lv := range m.LogicalVolumes {
+		writeLogicalVolume(&b, lv)
+	}
+	b.WriteString("\t}\n")
+
+	b.WriteString("}\n")
+	return b.String(), nil
+}
+
+func writePhysicalVolume(b *strings.Builder, pv PhysicalVolume) {
+	fmt.Fprintf(b, "\n\t\t%s {\n", pv.Name)
+	fmt.Fprintf(b, "\t\t\tid = %s\n", quote(pv.ID))
+	fmt.Fprintf(b, "\t\t\tdevice = %s\n", quote(pv.Device))
+	fmt.Fprintf(b, "\t\t\tstatus = %s\n", quoteList(pv.Status))
+	fmt.Fprintf(b, "\t\t\tdev_size = %d\n", pv.DevSize)
+	fmt.Fprintf(b, "\t\t\tpe_start = %d\n", pv.PEStart)
+	fmt.Fprintf(b, "\t\t\tpe_count = %d\n", pv.PECount)
+	b.WriteString("\t\t}\n")
+}
+
+func writeLogicalVolume(b *strings.Builder, lv LogicalVolume) {
+	fmt.Fprintf(b, "\n\t\t%s {\n", lv.Name)
+	fmt.Fprintf(b, "\t\t\tid = %s\n", quote(lv.ID))
+	fmt.Fprintf(b, "\t\t\tstatus = %s\n", quoteList(lv.Status))
+	fmt.Fprintf(b, "\n\t\t\tsegment_count = %d\n", len(lv.Segments))
+	for i, seg := range lv.Segments {
+		writeSegment(b, i+1, seg)
+	}
+	b.WriteString("\t\t}\n")
+}
+
+func writeSegment(b *strings.Builder, n int, seg Segment) {
+	fmt.Fprintf(b, "\n\t\t\tsegment%d {\n", n)
+	fmt.Fprintf(b, "\t\t\t\tstart_extent = %d\n", seg.StartExtent)
+	fmt.Fprintf(b, "\t\t\t\textent_count = %d\n", seg.ExtentCount)
+	fmt.Fprintf(b, "\n\t\t\t\ttype = %s\n", quote(seg.Type))
+
+	switch seg.Type {
+	case "striped", "linear", "mirror", "raid1":
+		fmt.Fprintf(b, "\t\t\t\tstripe_count = %d\n", seg.StripeCount)
+		if seg.StripeSize > 0 {
+			fmt.Fprintf(b, "\t\t\t\tstripe_size = %d\n", seg.StripeSize)
+		}
+		b.WriteString("\n\t\t\t\tstripes = [\n")
+		for i, s := range seg.Stripes {
+			sep := ","
+			if i == len(seg.Stripes)-1 {
+				sep = ""
+			}
+			fmt.Fprintf(b, "\t\t\t\t\t%s, %d%s\n", quote(s.PVName), s.StartExtent, sep)
+		}
+		b.WriteString("\t\t\t\t]\n")
+	case "thin-pool":
+		fmt.Fprintf(b, "\t\t\t\tmetadata = %s\n", quote(seg.PoolMetadata))
+		fmt.Fprintf(b, "\t\t\t\tpool = %s\n", quote(seg.PoolData))
+		fmt.Fprintf(b, "\t\t\t\tchunk_size = %d\n", seg.ChunkSize)
+		fmt.Fprintf(b, "\t\t\t\tzero_new_blocks = %d\n", seg.ZeroNewBlocks)
+	case "thin":
+		fmt.Fprintf(b, "\t\t\t\tthin_pool = %s\n", quote(seg.ThinPool))
+		fmt.Fprintf(b, "\t\t\t\tdevice_id = %d\n", seg.DeviceID)
+	case "snapshot", "snapshot-origin":
+		fmt.Fprintf(b, "\t\t\t\torigin = %s\n", quote(seg.Origin))
+		if seg.COWStore != "" {
+			fmt.Fprintf(b, "\t\t\t\tcow_store = %s\n", quote(seg.COWStore))
+		}
+	}
+
+	b.WriteString("\t\t\t}\n")
+}
+
+func quote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+func quoteList(ss []string) string {
+	quoted := make([]string, len(ss))
+	for i, s := range ss {
+		quoted[i] = quote(s)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
@@ -0,0 +1,305 @@
+package types
+
+import "strings"
+
+// Metadata is the root of the participle grammar used to parse LVM2's
+// text metadata format (the same format `vgcfgbackup` produces): a single
+// named top-level section, the volume group, containing nested sections
+// and key/value assignments.
+type Metadata struct {
+	VolumeGroup *Section `@@`
+}
+
+// Section is a named, brace-delimited block containing any number of
+// nested sections and assignments, e.g. `physical_volumes { pv0 { ... } }`.
+type Section struct {
+	Name    string   `@Ident "{"`
+	Entries []*Entry `@@* "}"`
+}
+
+// Entry is either a nested Section or a key/value Assignment.
+type Entry struct {
+	Section    *Section    `( @@`
+	Assignment *Assignment `| @@ )`
+}
+
+// Assignment is a single `key = value` pair.
+type Assignment struct {
+	Key   string `@Ident "="`
+	Value *Value `@@`
+}
+
+// Value is any right-hand side of an Assignment: a string, a number, or a
+// bracketed, comma-separated list of values.
+type Value struct {
+	String *string  `  @String`
+	Number *float64 `| @Number`
+	List   []*Value `| "[" ( @@ ( "," @@ )* )? "]"`
+}
+
+// MainSection is the parsed, typed representation of a volume group's
+// metadata text, as returned by ParseMainSection.
+type MainSection struct {
+	Name string
+
+	ID         string
+	Seqno      int64
+	Format     string
+	Status     []string
+	Flags      []string
+	ExtentSize int64
+	MaxLV      int64
+	MaxPV      int64
+
+	PhysicalVolumes []PhysicalVolume
+	LogicalVolumes  []LogicalVolume
+}
+
+// PhysicalVolume is one `physical_volumes { pvN { ... } }` entry.
+type PhysicalVolume struct {
+	Name string
+
+	ID      string
+	Device  string
+	Status  []string
+	DevSize int64
+	PEStart int64
+	PECount int64
+}
+
+// LogicalVolume is one `logical_volumes { lvN { ... } }` entry.
+type LogicalVolume struct {
+	Name string
+
+	ID       string
+	Status   []string
+	Segments []Segment
+}
+
+// Stripe is one (physical volume, starting extent) pair within a striped
+// or mirrored Segment's `stripes` list.
+type Stripe struct {
+	PVName      string
+	StartExtent int64
+}
+
+// Segment is one `segmentN { ... }` entry of a logical volume, describing
+// a contiguous run of logical extents and how it maps onto one or more
+// physical volumes. Fields below are grouped by the segment Type that
+// populates them; most fields on a given Segment value are zero, since
+// only the group matching Type is ever set.
+type Segment struct {
+	StartExtent int64
+	ExtentCount int64
+	Type        string // "striped", "linear", "mirror", "raid1", "thin-pool", "thin", "snapshot", "snapshot-origin"
+
+	// striped / linear / mirror / raid1
+	StripeCount int64
+	StripeSize  int64
+	Stripes     []Stripe
+
+	// thin-pool
+	PoolMetadata  string
+	PoolData      string
+	ChunkSize     int64
+	ZeroNewBlocks int64
+
+	// thin
+	ThinPool string
+	DeviceID int64
+
+	// snapshot / snapshot-origin
+	Origin   string
+	COWStore string
+}
+
+// ParseMainSection walks the generic Metadata AST produced by the
+// participle grammar above and builds the typed MainSection it describes.
+func ParseMainSection(m *Metadata) MainSection {
+	if m == nil || m.VolumeGroup == nil {
+		return MainSection{}
+	}
+	return parseVolumeGroup(m.VolumeGroup)
+}
+
+func parseVolumeGroup(s *Section) MainSection {
+	vg := MainSection{Name: s.Name}
+	for _, e := range s.Entries {
+		switch {
+		case e.Assignment != nil:
+			applyVGAssignment(&vg, e.Assignment)
+		case e.Section != nil:
+			switch e.Section.Name {
+			case "physical_volumes":
+				for _, pvEntry := range e.Section.Entries {
+					if pvEntry.Section != nil {
+						vg.PhysicalVolumes = append(vg.PhysicalVolumes, parsePhysicalVolume(pvEntry.Section))
+					}
+				}
+			case "logical_volumes":
+				for _, lvEntry := range e.Section.Entries {
+					if lvEntry.Section != nil {
+						vg.LogicalVolumes = append(vg.LogicalVolumes, parseLogicalVolume(lvEntry.Section))
+					}
+				}
+			}
+		}
+	}
+	return vg
+}
+
+func applyVGAssignment(vg *MainSection, a *Assignment) {
+	switch a.Key {
+	case "id":
+		vg.ID = valueString(a.Value)
+	case "seqno":
+		vg.Seqno = valueInt(a.Value)
+	case "format":
+		vg.Format = valueString(a.Value)
+	case "status":
+		vg.Status = valueStrings(a.Value)
+	case "flags":
+		vg.Flags = valueStrings(a.Value)
+	case "extent_size":
+		vg.ExtentSize = valueInt(a.Value)
+	case "max_lv":
+		vg.MaxLV = valueInt(a.Value)
+	case "max_pv":
+		vg.MaxPV = valueInt(a.Value)
+	}
+}
+
+func parsePhysicalVolume(s *Section) PhysicalVolume {
+	pv := PhysicalVolume{Name: s.Name}
+	for _, e := range s.Entries {
+		if e.Assignment == nil {
+			continue
+		}
+		a := e.Assignment
+		switch a.Key {
+		case "id":
+			pv.ID = valueString(a.Value)
+		case "device":
+			pv.Device = valueString(a.Value)
+		case "status":
+			pv.Status = valueStrings(a.Value)
+		case "dev_size":
+			pv.DevSize = valueInt(a.Value)
+		case "pe_start":
+			pv.PEStart = valueInt(a.Value)
+		case "pe_count":
+			pv.PECount = valueInt(a.Value)
+		}
+	}
+	return pv
+}
+
+func parseLogicalVolume(s *Section) LogicalVolume {
+	lv := LogicalVolume{Name: s.Name}
+	for _, e := range s.Entries {
+		switch {
+		case e.Assignment != nil:
+			a := e.Assignment
+			switch a.Key {
+			case "id":
+				lv.ID = valueString(a.Value)
+			case "status":
+				lv.Status = valueStrings(a.Value)
+			}
+		case e.Section != nil:
+			lv.Segments = append(lv.Segments, parseSegment(e.Section))
+		}
+	}
+	return lv
+}
+
+func parseSegment(s *Section) Segment {
+	var seg Segment
+	for _, e := range s.Entries {
+		if e.Assignment == nil {
+			continue
+		}
+		a := e.Assignment
+		switch a.Key {
+		case "start_extent":
+			seg.StartExtent = valueInt(a.Value)
+		case "extent_count":
+			seg.ExtentCount = valueInt(a.Value)
+		case "type":
+			seg.Type = valueString(a.Value)
+		case "stripe_count":
+			seg.StripeCount = valueInt(a.Value)
+		case "stripe_size":
+			seg.StripeSize = valueInt(a.Value)
+		case "stripes":
+			seg.Stripes = valueStripes(a.Value)
+		case "metadata":
+			seg.PoolMetadata = valueString(a.Value)
+		case "pool":
+			seg.PoolData = valueString(a.Value)
+		case "chunk_size":
+			seg.ChunkSize = valueInt(a.Value)
+		case "zero_new_blocks":
+			seg.ZeroNewBlocks = valueInt(a.Value)
+		case "thin_pool":
+			seg.ThinPool = valueString(a.Value)
+		case "device_id":
+			seg.DeviceID = valueInt(a.Value)
+		case "origin":
+			seg.Origin = valueString(a.Value)
+		case "cow_store":
+			seg.COWStore = valueString(a.Value)
+		}
+	}
+	return seg
+}
+
+// valueString returns a string Value's content with its surrounding
+// quotes removed and \" escapes undone, the inverse of marshal.go's
+// quote(). The lexer's String token captures the quotes as part of the
+// match, so every caller needs this rather than the raw token text.
+func valueString(v *Value) string {
+	if v == nil || v.String == nil {
+		return ""
+	}
+	s := *v.String
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		s = s[1 : len(s)-1]
+	}
+	return strings.ReplaceAll(s, `\"`, `"`)
+}
+
+func valueInt(v *Value) int64 {
+	if v == nil || v.Number == nil {
+		return 0
+	}
+	return int64(*v.Number)
+}
+
+func valueStrings(v *Value) []string {
+	if v == nil {
+		return nil
+	}
+	var out []string
+	for _, item := range v.List {
+		if item.String != nil {
+			out = append(out, valueString(item))
+		}
+	}
+	return out
+}
+
+// valueStripes decodes a `stripes = ["pv0", 0, "pv1", 0]` list into pairs
+// of (physical volume name, starting extent).
+func valueStripes(v *Value) []Stripe {
+	if v == nil {
+		return nil
+	}
+	var out []Stripe
+	for i := 0; i+1 < len(v.List); i += 2 {
+		name := valueString(v.List[i])
+		start := valueInt(v.List[i+1])
+		out = append(out, Stripe{PVName: name, StartExtent: start})
+	}
+	return out
+}
@@ -0,0 +1,176 @@
+package types
+
+import (
+	"io"
+
+	"golang.org/x/xerrors"
+)
+
+// sectorSize is the size, in bytes, of the sector units that pe_start,
+// extent_size, and stripe_size are expressed in. It mirrors lvm.SectorSize;
+// it is duplicated here so this package does not need to import lvm.
+const sectorSize = 512
+
+// Size returns the logical volume's size in bytes.
+func (lv *ResolvedLogicalVolume) Size() int64 {
+	var n int64
+	for _, seg := range lv.Segments {
+		n += seg.ExtentCount * lv.ExtentSize * sectorSize
+	}
+	return n
+}
+
+// NewReaderAt returns an io.ReaderAt that reads the logical volume's data,
+// translating LV-relative offsets into physical volume reads through each
+// segment's resolved Areas.
+func (lv *ResolvedLogicalVolume) NewReaderAt() (io.ReaderAt, error) {
+	for _, seg := range lv.Segments {
+		for _, area := range seg.Areas {
+			if area.PV == nil || area.PV.Volume == nil || area.PV.Volume.Reader == nil {
+				return nil, xerrors.Errorf("logical volume %s has an unresolved segment area", lv.Name)
+			}
+		}
+	}
+	return &logicalVolumeReaderAt{lv: lv}, nil
+}
+
+// NewSectionReader is a convenience wrapper around NewReaderAt that
+// returns an io.SectionReader spanning the whole logical volume.
+func (lv *ResolvedLogicalVolume) NewSectionReader() (*io.SectionReader, error) {
+	ra, err := lv.NewReaderAt()
+	if err != nil {
+		return nil, err
+	}
+	return io.NewSectionReader(ra, 0, lv.Size()), nil
+}
+
+type logicalVolumeReaderAt struct {
+	lv *ResolvedLogicalVolume
+}
+
+func (r *logicalVolumeReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	lv := r.lv
+	size := lv.Size()
+	if off < 0 {
+		return 0, xerrors.New("lvm: negative offset")
+	}
+	if off >= size {
+		return 0, io.EOF
+	}
+
+	var read int
+	for len(p) > 0 {
+		if off >= size {
+			break
+		}
+		seg, segOff, segSize := segmentAt(lv, off)
+		if seg == nil {
+			return read, xerrors.Errorf("lvm: offset %d is not covered by any segment", off)
+		}
+
+		want := segSize - segOff
+		if int64(len(p)) < want {
+			want = int64(len(p))
+		}
+
+		n, err := readSegment(seg, lv.ExtentSize, segOff, p[:want])
+		read += n
+		off += int64(n)
+		p = p[n:]
+		if err != nil {
+			return read, err
+		}
+	}
+
+	if read == 0 {
+		return 0, io.EOF
+	}
+	return read, nil
+}
+
+// segmentAt finds the segment of lv covering byte offset off, returning
+// it along with off translated to a segment-relative byte offset and the
+// segment's total size in bytes.
+func segmentAt(lv *ResolvedLogicalVolume, off int64) (seg *ResolvedSegment, segOff, segSize int64) {
+	bytesPerExtent := lv.ExtentSize * sectorSize
+	var base int64
+	for i := range lv.Segments {
+		s := &lv.Segments[i]
+		size := s.ExtentCount * bytesPerExtent
+		if off < base+size {
+			return s, off - base, size
+		}
+		base += size
+	}
+	return nil, 0, 0
+}
+
+// readSegment reads a segment-relative byte range out of a single
+// segment's underlying physical areas.
+func readSegment(seg *ResolvedSegment, extentSizeSectors, segOff int64, p []byte) (int, error) {
+	bytesPerExtent := extentSizeSectors * sectorSize
+
+	switch seg.Type {
+	case "striped":
+		return readStriped(seg, bytesPerExtent, segOff, p)
+	case "linear", "snapshot-origin":
+		if len(seg.Areas) != 1 {
+			return 0, xerrors.Errorf("lvm: %s segment has %d areas, want 1", seg.Type, len(seg.Areas))
+		}
+		return readArea(seg.Areas[0], bytesPerExtent, segOff, p)
+	case "mirror", "raid1":
+		var lastErr error
+		for _, area := range seg.Areas {
+			n, err := readArea(area, bytesPerExtent, segOff, p)
+			if err == nil {
+				return n, nil
+			}
+			lastErr = err
+		}
+		if lastErr == nil {
+			lastErr = xerrors.New("lvm: mirror segment has no legs")
+		}
+		return 0, xerrors.Errorf("lvm: all mirror legs failed: %w", lastErr)
+	case "thin":
+		return readThin(seg, segOff, p)
+	case "snapshot":
+		return readSnapshot(seg, segOff, p)
+	case "thin-pool":
+		return 0, xerrors.New("lvm: thin-pool segments hold no LV data of their own, read the thin LVs that use them")
+	default:
+		return 0, xerrors.Errorf("lvm: unsupported segment type %q", seg.Type)
+	}
+}
+
+// readStriped reads from a striped segment, round-robining across Areas
+// at stripe_size granularity.
+func readStriped(seg *ResolvedSegment, bytesPerExtent, segOff int64, p []byte) (int, error) {
+	nStripes := int64(len(seg.Areas))
+	if nStripes == 0 {
+		return 0, xerrors.New("lvm: striped segment has no areas")
+	}
+	stripeSize := seg.StripeSize * sectorSize
+	if stripeSize == 0 {
+		stripeSize = bytesPerExtent
+	}
+
+	stripeIndex := (segOff / stripeSize) % nStripes
+	chunkIndex := segOff / stripeSize / nStripes
+	withinChunk := segOff % stripeSize
+
+	// Don't cross a stripe chunk boundary in one read; the caller loops.
+	if want := stripeSize - withinChunk; int64(len(p)) > want {
+		p = p[:want]
+	}
+
+	areaOff := chunkIndex*stripeSize + withinChunk
+	return readArea(seg.Areas[stripeIndex], bytesPerExtent, areaOff, p)
+}
+
+// readArea reads from a single (pv, pe_start) area at an area-relative
+// byte offset.
+func readArea(area SegmentArea, bytesPerExtent, areaOff int64, p []byte) (int, error) {
+	pv := area.PV
+	physOff := pv.PEStart*sectorSize + area.PEStart*bytesPerExtent + areaOff
+	return pv.Volume.Reader.ReadAt(p, physOff)
+}
@@ -0,0 +1,99 @@
+package types
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// buildThinBtreeNode lays out one dm-persistent-data btree node: the
+// 32-byte header (checksum, flags, blocknr, nr_entries, max_entries,
+// value_size, padding) followed by up to maxEntries keys and then up to
+// maxEntries values, each valueSize bytes, exactly as btreeLookup reads
+// them back.
+func buildThinBtreeNode(t *testing.T, blockSize int64, leaf bool, maxEntries int, valueSize int, keys []uint64, values [][]byte) []byte {
+	t.Helper()
+	buf := make([]byte, blockSize)
+
+	var flags uint32
+	if leaf {
+		flags = btreeFlagLeaf
+	} else {
+		flags = btreeFlagInternal
+	}
+	binary.LittleEndian.PutUint32(buf[4:8], flags)
+	binary.LittleEndian.PutUint32(buf[16:20], uint32(len(keys)))
+	binary.LittleEndian.PutUint32(buf[20:24], uint32(maxEntries))
+	binary.LittleEndian.PutUint32(buf[24:28], uint32(valueSize))
+
+	const headerSize = 32
+	for i, k := range keys {
+		binary.LittleEndian.PutUint64(buf[headerSize+8*i:], k)
+	}
+	valuesOff := headerSize + maxEntries*8
+	for i, v := range values {
+		copy(buf[valuesOff+i*valueSize:], v)
+	}
+	return buf
+}
+
+// buildThinMetadataDevice lays out a minimal synthetic dm-thin metadata
+// device: a superblock at block 0 whose data_mapping_root points at a
+// one-entry device-id tree (block 1), whose sole value is the block
+// number of that device's own mapping tree (block 2), which in turn
+// holds a single virtual-block -> block_time mapping.
+func buildThinMetadataDevice(t *testing.T, deviceID int64, virtualBlock, poolBlock uint64) []byte {
+	t.Helper()
+	const blockSize = 512
+
+	blockTime := make([]byte, 8)
+	binary.LittleEndian.PutUint64(blockTime, poolBlock<<24)
+	mappingTree := buildThinBtreeNode(t, blockSize, true, 1, 8, []uint64{virtualBlock}, [][]byte{blockTime})
+
+	devRoot := make([]byte, 8)
+	binary.LittleEndian.PutUint64(devRoot, 2) // mappingTree is block 2
+	deviceTree := buildThinBtreeNode(t, blockSize, true, 1, 8, []uint64{uint64(deviceID)}, [][]byte{devRoot})
+
+	var sb thinSuperblock
+	sb.Magic = thinSuperblockMagic
+	sb.MetadataBlockSize = 1 // sectors, so blockSize == sectorSize == 512
+	sb.DataMappingRoot = 1   // deviceTree is block 1
+
+	var sbuf bytes.Buffer
+	if err := binary.Write(&sbuf, binary.LittleEndian, sb); err != nil {
+		t.Fatalf("encode superblock: %v", err)
+	}
+	superblock := make([]byte, blockSize)
+	copy(superblock, sbuf.Bytes())
+
+	image := make([]byte, 3*blockSize)
+	copy(image[0*blockSize:], superblock)
+	copy(image[1*blockSize:], deviceTree)
+	copy(image[2*blockSize:], mappingTree)
+	return image
+}
+
+// TestLookupThinMapping builds a minimal synthetic thin metadata device
+// (superblock + device-id tree + one mapping-tree leaf) and checks
+// lookupThinMapping walks both btree levels to resolve the expected pool
+// block.
+func TestLookupThinMapping(t *testing.T) {
+	const deviceID = 3
+	const virtualBlock = 42
+	const poolBlock = 99
+
+	image := buildThinMetadataDevice(t, deviceID, virtualBlock, poolBlock)
+	meta := bytes.NewReader(image)
+
+	got, err := lookupThinMapping(meta, deviceID, virtualBlock)
+	if err != nil {
+		t.Fatalf("lookupThinMapping: %v", err)
+	}
+	if got != poolBlock {
+		t.Errorf("got pool block %d, want %d", got, poolBlock)
+	}
+
+	if _, err := lookupThinMapping(meta, deviceID, virtualBlock+1); err == nil {
+		t.Error("lookupThinMapping on an unmapped virtual block: got nil error, want one")
+	}
+}
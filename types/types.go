@@ -0,0 +1,113 @@
+// Package types defines the on-disk structures of the LVM2 label and
+// metadata formats, plus the parsed, in-memory object model built on top
+// of them.
+package types
+
+import "io"
+
+// PhysicalVolumeLabelHeader is the "LABELONE" sector that identifies a disk
+// as an LVM2 physical volume and points at the PhysicalVolumeHeader that
+// follows it.
+type PhysicalVolumeLabelHeader struct {
+	ID     [8]byte
+	Sector uint64
+	CRC    uint32
+	Offset uint32
+	Type   [8]byte
+}
+
+// PhysicalVolumeHeader describes a single physical volume: its identity,
+// size, and the data/metadata areas that live on it.
+type PhysicalVolumeHeader struct {
+	PhysicalVolumeIdentifier [32]byte
+	PhysicalVolumeSize       int64
+
+	DataAreaDescriptor     []DataAreaDescriptor
+	MetaDataAreaDescriptor []DataAreaDescriptor
+}
+
+// DataAreaDescriptor is a (offset, size) pair pointing at a data or
+// metadata area on the physical volume. A descriptor whose offset and
+// size are both 0 marks the end of a NULL-terminated list of descriptors.
+type DataAreaDescriptor struct {
+	DataAreaOffset int64
+	DataAreaSize   int64
+}
+
+// RawLocationFlagIgnored marks a raw_locn entry as superseded/invalid: it
+// must not be treated as a live metadata copy.
+const RawLocationFlagIgnored uint32 = 1
+
+// RawLocationDescriptor (raw_locn) points at one committed metadata text
+// copy within a metadata area's circular buffer, along with the CRC32
+// that protects it.
+type RawLocationDescriptor struct {
+	DataAreaOffset int64
+	DataAreaSize   int64
+	Checksum       uint32
+	Flags          uint32
+}
+
+// MetadataAreaHeader is the fixed-size header of a metadata area's
+// circular buffer (mda_header). It is followed on disk by a
+// NULL-terminated list of RawLocationDescriptors, read separately.
+type MetadataAreaHeader struct {
+	Checksum uint32
+	Magic    [16]byte
+	Version  uint32
+	Start    int64
+	Size     int64
+}
+
+// MetadataCopy is a single committed metadata text copy read out of a
+// metadata area's circular buffer, with its CRC32 already verified.
+type MetadataCopy struct {
+	Offset   int64
+	Size     int64
+	Checksum uint32
+	Seqno    int64
+
+	// Raw is the metadata text blob, with its trailing NUL stripped.
+	Raw string
+	// Metadata is Raw parsed into the typed object model.
+	Metadata MainSection
+}
+
+// MetadataArea is a parsed metadata area: its header, the raw location
+// descriptors found in it, and every valid committed metadata copy they
+// point at, sorted newest (highest seqno) first.
+type MetadataArea struct {
+	Header                 MetadataAreaHeader
+	RawLocationDescriptors []RawLocationDescriptor
+	Copies                 []MetadataCopy
+}
+
+// Volume is a single physical volume as parsed from a PV label, PV header,
+// and its metadata areas.
+//
+// Volume is analogous to debug/pe.File: it is normally created by Open,
+// which also retains the underlying io.Closer so callers can release the
+// backing file with Close. Volumes created with NewVolume from an
+// in-memory io.ReadSeeker have no closer and Close is a no-op.
+type Volume struct {
+	LabelHeader  PhysicalVolumeLabelHeader
+	Header       PhysicalVolumeHeader
+	MetadataArea []MetadataArea
+
+	// Closer, if set, is closed by Close. Open sets this to the
+	// underlying *os.File.
+	Closer io.Closer
+
+	// Reader is used to read physical extents off this volume, e.g. by
+	// ResolvedLogicalVolume.NewReaderAt. NewVolume and Open both set it.
+	Reader io.ReaderAt
+}
+
+// Close closes the Volume's underlying io.Closer, if any. It is safe to
+// call on a Volume created with NewVolume.
+func (v *Volume) Close() error {
+	if v.Closer == nil {
+		return nil
+	}
+	return v.Closer.Close()
+}
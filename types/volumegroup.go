@@ -0,0 +1,124 @@
+package types
+
+// VolumeGroup is a fully assembled LVM2 volume group: the set of physical
+// volumes that make it up, linked against the single, most-recent VG
+// metadata text that describes how logical volumes are laid out across
+// them.
+type VolumeGroup struct {
+	ID    string
+	Name  string
+	Seqno int64
+
+	PhysicalVolumes []*GroupPhysicalVolume
+
+	logicalVolumes []*ResolvedLogicalVolume
+}
+
+// GroupPhysicalVolume is one physical volume belonging to a VolumeGroup,
+// linking the metadata's view of it (name, ID, extent count) to the
+// parsed Volume read off disk.
+type GroupPhysicalVolume struct {
+	Name    string // metadata-internal name, e.g. "pv0"
+	ID      string
+	Device  string
+	PEStart int64
+	PECount int64
+
+	Volume *Volume
+}
+
+// ResolvedLogicalVolume is a logical volume whose segments have been
+// resolved against the volume group's physical volumes, so each segment's
+// extents can be translated directly into physical reads.
+type ResolvedLogicalVolume struct {
+	Name string
+	ID   string
+
+	// ExtentSize is the volume group's extent size, in sectors. Segment
+	// extent counts and offsets are expressed in units of this size.
+	ExtentSize int64
+
+	Segments []ResolvedSegment
+}
+
+// ResolvedSegment is one segment of a logical volume's address space,
+// mapped onto one or more physical volume extent ranges.
+type ResolvedSegment struct {
+	StartExtent int64
+	ExtentCount int64
+	Type        string // "striped", "linear", "mirror", "raid1", "thin-pool", "thin", "snapshot", "snapshot-origin"
+
+	StripeCount int64
+	StripeSize  int64
+
+	// Areas holds, in order, the physical extent range each stripe or
+	// mirror leg contributes. Striped segments round-robin across Areas;
+	// linear segments have exactly one; mirror/raid1 segments list one
+	// full-length area per leg.
+	Areas []SegmentArea
+
+	// Raw is the segment as parsed from the metadata text, for segment
+	// types (thin-pool, thin, snapshot, ...) that Areas does not yet
+	// resolve.
+	Raw Segment
+
+	// The following are set by the volume group assembler's second
+	// resolution pass, once every logical volume's ResolvedLogicalVolume
+	// exists, to link segments that reference other logical volumes by
+	// name.
+
+	// PoolLV is the thin pool a "thin" segment's virtual blocks are
+	// allocated from.
+	PoolLV *ResolvedLogicalVolume
+	// DeviceID is a "thin" segment's device id within its thin pool's
+	// metadata.
+	DeviceID int64
+
+	// PoolMetadataLV and PoolDataLV are a "thin-pool" segment's internal
+	// metadata (dm-thin btree) and data logical volumes.
+	PoolMetadataLV *ResolvedLogicalVolume
+	PoolDataLV     *ResolvedLogicalVolume
+
+	// OriginLV and COWLV are a "snapshot" segment's origin and
+	// exception-store (COW) logical volumes.
+	OriginLV *ResolvedLogicalVolume
+	COWLV    *ResolvedLogicalVolume
+}
+
+// SegmentArea is a physical volume extent range contributed to a segment.
+type SegmentArea struct {
+	PV          *GroupPhysicalVolume
+	PEStart     int64
+	ExtentCount int64
+}
+
+// LogicalVolumes returns the volume group's logical volumes with their
+// segments resolved against its physical volumes.
+func (vg *VolumeGroup) LogicalVolumes() []*ResolvedLogicalVolume {
+	return vg.logicalVolumes
+}
+
+// SetLogicalVolumes installs the resolved logical volumes for the group.
+// It exists so that assembly code outside this package (lvm.NewVolumeGroup)
+// can populate the unexported logicalVolumes field.
+func (vg *VolumeGroup) SetLogicalVolumes(lvs []*ResolvedLogicalVolume) {
+	vg.logicalVolumes = lvs
+}
+
+// Close closes every physical volume's underlying Volume. It is a no-op
+// for any physical volume whose Volume was not opened with a Closer (as
+// with a VolumeGroup assembled by NewVolumeGroup rather than OpenGroup).
+// The first error encountered is returned, after every Volume has been
+// given a chance to close.
+func (vg *VolumeGroup) Close() error {
+	var first error
+	for _, pv := range vg.PhysicalVolumes {
+		if pv.Volume == nil {
+			continue
+		}
+		if err := pv.Volume.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
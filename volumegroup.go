@@ -0,0 +1,269 @@
+package lvm
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/masahiro331/go-lvm/types"
+	"golang.org/x/xerrors"
+)
+
+// OpenGroup opens every path as a physical volume image and assembles
+// them into a single VolumeGroup. The returned group owns all of the
+// files it opened, Closer included, so VolumeGroup.Close releases them.
+// If any path fails to open, or the group fails to assemble, every file
+// already opened is closed before the error is returned.
+func OpenGroup(paths ...string) (*types.VolumeGroup, error) {
+	files := make([]*os.File, 0, len(paths))
+	closeAll := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+
+	rs := make([]io.ReadSeeker, 0, len(paths))
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			closeAll()
+			return nil, xerrors.Errorf("failed to open %s: %w", path, err)
+		}
+		files = append(files, f)
+		rs = append(rs, f)
+	}
+
+	volumes, err := readVolumes(rs)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	for i, v := range volumes {
+		v.Closer = files[i]
+	}
+
+	vg, err := assembleVolumeGroup(volumes)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	return vg, nil
+}
+
+// NewVolumeGroup reads a physical volume off each of rs and assembles
+// them into a single VolumeGroup: it matches physical volumes by VG UUID,
+// picks the highest-seqno committed metadata copy across all of their
+// metadata areas, verifies every physical volume the metadata lists is
+// present, and resolves every logical volume's segments against the
+// assembled set of physical volumes. Unlike OpenGroup, the caller retains
+// ownership of rs and VolumeGroup.Close on the result is a no-op.
+func NewVolumeGroup(rs ...io.ReadSeeker) (*types.VolumeGroup, error) {
+	volumes, err := readVolumes(rs)
+	if err != nil {
+		return nil, err
+	}
+	return assembleVolumeGroup(volumes)
+}
+
+// readVolumes parses a physical volume off each of rs.
+func readVolumes(rs []io.ReadSeeker) ([]*types.Volume, error) {
+	if len(rs) == 0 {
+		return nil, xerrors.New("no physical volumes given")
+	}
+
+	volumes := make([]*types.Volume, 0, len(rs))
+	for i, r := range rs {
+		v, err := NewVolume(r)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to read physical volume %d: %w", i, err)
+		}
+		volumes = append(volumes, v)
+	}
+	return volumes, nil
+}
+
+// assembleVolumeGroup links already-parsed physical volumes into a
+// VolumeGroup, as described by NewVolumeGroup.
+func assembleVolumeGroup(volumes []*types.Volume) (*types.VolumeGroup, error) {
+	canonical, err := canonicalMetadata(volumes)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to find volume group metadata: %w", err)
+	}
+
+	pvs, err := matchPhysicalVolumes(canonical, volumes)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to match physical volumes: %w", err)
+	}
+
+	vg := &types.VolumeGroup{
+		ID:              canonical.ID,
+		Name:            canonical.Name,
+		Seqno:           canonical.Seqno,
+		PhysicalVolumes: pvs,
+	}
+	vg.SetLogicalVolumes(resolveLogicalVolumes(canonical, pvs))
+
+	return vg, nil
+}
+
+// canonicalMetadata returns the highest-seqno metadata copy found across
+// every metadata area of every volume, after checking they all agree on
+// which volume group they belong to.
+func canonicalMetadata(volumes []*types.Volume) (types.MainSection, error) {
+	var best types.MainSection
+	found := false
+
+	for _, v := range volumes {
+		for _, mda := range v.MetadataArea {
+			for _, mc := range mda.Copies {
+				m := mc.Metadata
+				if m.ID == "" {
+					continue
+				}
+				if found && m.ID != best.ID {
+					return types.MainSection{}, xerrors.Errorf("physical volumes belong to different volume groups: %s != %s", m.ID, best.ID)
+				}
+				if !found || m.Seqno > best.Seqno {
+					best = m
+					found = true
+				}
+			}
+		}
+	}
+
+	if !found {
+		return types.MainSection{}, xerrors.New("no volume group metadata found on any physical volume")
+	}
+	return best, nil
+}
+
+// matchPhysicalVolumes links every physical_volumes entry in vg to the
+// Volume it was read from (matched by UUID) and errors if any are
+// missing.
+func matchPhysicalVolumes(vg types.MainSection, volumes []*types.Volume) ([]*types.GroupPhysicalVolume, error) {
+	byUUID := make(map[string]*types.Volume, len(volumes))
+	for _, v := range volumes {
+		byUUID[pvUUID(v)] = v
+	}
+
+	pvs := make([]*types.GroupPhysicalVolume, 0, len(vg.PhysicalVolumes))
+	for _, pv := range vg.PhysicalVolumes {
+		v, ok := byUUID[pv.ID]
+		if !ok {
+			return nil, xerrors.Errorf("physical volume %s (%s) listed in metadata was not supplied", pv.Name, pv.ID)
+		}
+		pvs = append(pvs, &types.GroupPhysicalVolume{
+			Name:    pv.Name,
+			ID:      pv.ID,
+			Device:  pv.Device,
+			PEStart: pv.PEStart,
+			PECount: pv.PECount,
+			Volume:  v,
+		})
+	}
+	return pvs, nil
+}
+
+// pvUUID returns a physical volume's UUID in the same textual form it
+// appears in VG metadata.
+func pvUUID(v *types.Volume) string {
+	id := strings.TrimRight(string(v.Header.PhysicalVolumeIdentifier[:]), "\x00")
+	if len(id) != 32 {
+		return id
+	}
+	// The PV header stores the 32 characters bare; metadata text writes
+	// them in dash-separated groups of 6-4-4-4-4-4-6.
+	return strings.Join([]string{id[0:6], id[6:10], id[10:14], id[14:18], id[18:22], id[22:26], id[26:32]}, "-")
+}
+
+// resolveLogicalVolumes translates every logical volume's segments from
+// metadata-text form (pv name + starting extent) to resolved areas
+// pointing directly at a *types.GroupPhysicalVolume.
+func resolveLogicalVolumes(vg types.MainSection, pvs []*types.GroupPhysicalVolume) []*types.ResolvedLogicalVolume {
+	byName := make(map[string]*types.GroupPhysicalVolume, len(pvs))
+	for _, pv := range pvs {
+		byName[pv.Name] = pv
+	}
+
+	lvs := make([]*types.ResolvedLogicalVolume, 0, len(vg.LogicalVolumes))
+	rlvByName := make(map[string]*types.ResolvedLogicalVolume, len(vg.LogicalVolumes))
+	for _, lv := range vg.LogicalVolumes {
+		rlv := &types.ResolvedLogicalVolume{Name: lv.Name, ID: lv.ID, ExtentSize: vg.ExtentSize}
+		for _, seg := range lv.Segments {
+			rlv.Segments = append(rlv.Segments, resolveSegment(seg, byName))
+		}
+		lvs = append(lvs, rlv)
+		rlvByName[lv.Name] = rlv
+	}
+
+	// Second pass: thin-pool, thin and snapshot segments reference other
+	// logical volumes of this same group by name (pool, metadata/data,
+	// origin/COW), which can only be resolved once every
+	// ResolvedLogicalVolume above exists.
+	for _, rlv := range lvs {
+		for i := range rlv.Segments {
+			linkSegmentVolumes(&rlv.Segments[i], rlvByName)
+		}
+	}
+
+	return lvs
+}
+
+func linkSegmentVolumes(seg *types.ResolvedSegment, lvs map[string]*types.ResolvedLogicalVolume) {
+	switch seg.Type {
+	case "thin-pool":
+		seg.PoolMetadataLV = lvs[seg.Raw.PoolMetadata]
+		seg.PoolDataLV = lvs[seg.Raw.PoolData]
+	case "thin":
+		seg.PoolLV = lvs[seg.Raw.ThinPool]
+		seg.DeviceID = seg.Raw.DeviceID
+	case "snapshot":
+		seg.OriginLV = lvs[seg.Raw.Origin]
+		seg.COWLV = lvs[seg.Raw.COWStore]
+	}
+}
+
+func resolveSegment(seg types.Segment, pvs map[string]*types.GroupPhysicalVolume) types.ResolvedSegment {
+	rseg := types.ResolvedSegment{
+		StartExtent: seg.StartExtent,
+		ExtentCount: seg.ExtentCount,
+		Type:        seg.Type,
+		StripeCount: seg.StripeCount,
+		StripeSize:  seg.StripeSize,
+		Raw:         seg,
+	}
+
+	switch seg.Type {
+	case "striped":
+		perStripe := seg.ExtentCount
+		if seg.StripeCount > 0 {
+			perStripe = seg.ExtentCount / seg.StripeCount
+		}
+		for _, s := range seg.Stripes {
+			rseg.Areas = append(rseg.Areas, types.SegmentArea{
+				PV:          pvs[s.PVName],
+				PEStart:     s.StartExtent,
+				ExtentCount: perStripe,
+			})
+		}
+	case "linear", "snapshot-origin":
+		if len(seg.Stripes) > 0 {
+			s := seg.Stripes[0]
+			rseg.Areas = append(rseg.Areas, types.SegmentArea{
+				PV:          pvs[s.PVName],
+				PEStart:     s.StartExtent,
+				ExtentCount: seg.ExtentCount,
+			})
+		}
+	case "mirror", "raid1":
+		for _, s := range seg.Stripes {
+			rseg.Areas = append(rseg.Areas, types.SegmentArea{
+				PV:          pvs[s.PVName],
+				PEStart:     s.StartExtent,
+				ExtentCount: seg.ExtentCount,
+			})
+		}
+	}
+
+	return rseg
+}
@@ -0,0 +1,184 @@
+package lvm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/masahiro331/go-lvm/types"
+	"golang.org/x/xerrors"
+)
+
+// Writer commits new metadata copies into an existing LVM2 metadata area
+// in place, the way `vgcfgbackup --restore`/the LVM2 library itself do:
+// marshal the text, CRC32 it, drop it into the next ring buffer slot, and
+// rewrite the raw_locn entries and mda_header checksum that point at it.
+// A Writer does not touch the label or PV header; the metadata area it
+// writes to must already exist.
+type Writer struct {
+	rws        io.ReadWriteSeeker
+	descriptor types.DataAreaDescriptor
+}
+
+// NewWriter returns a Writer that commits metadata into the metadata area
+// described by descriptor (as found on types.PhysicalVolumeHeader's
+// MetaDataAreaDescriptor) on rws.
+func NewWriter(rws io.ReadWriteSeeker, descriptor types.DataAreaDescriptor) *Writer {
+	return &Writer{rws: rws, descriptor: descriptor}
+}
+
+// WriteMetadata commits m as a new metadata copy into mda: it bumps m's
+// seqno past every copy mda already has, serializes and CRC32s it,
+// appends it into the ring buffer after the current newest copy, rotates
+// mda's raw_locn slots to reference it, and rewrites the mda_header
+// checksum. It returns mda updated to reflect the write, with the new
+// copy as its newest.
+func (w *Writer) WriteMetadata(mda types.MetadataArea, m types.MainSection) (types.MetadataArea, error) {
+	m.Seqno = nextSeqno(mda)
+	text, err := m.Marshal()
+	if err != nil {
+		return types.MetadataArea{}, xerrors.Errorf("failed to marshal metadata: %w", err)
+	}
+	raw := []byte(text + "\x00")
+	checksum := crc32LVM(raw)
+
+	ringSize := mda.Header.Size
+	areaStart := w.descriptor.DataAreaOffset
+	offset := nextRingOffset(mda, ringSize)
+
+	if err := writeRingBuffer(w.rws, areaStart, ringSize, offset, raw); err != nil {
+		return types.MetadataArea{}, xerrors.Errorf("failed to write metadata copy: %w", err)
+	}
+
+	loc := types.RawLocationDescriptor{
+		DataAreaOffset: offset,
+		DataAreaSize:   int64(len(raw)),
+		Checksum:       checksum,
+	}
+	locs := setLocationSlot(mda, loc)
+	if err := writeMetadataAreaHeader(w.rws, w.descriptor.DataAreaOffset, mda.Header, locs); err != nil {
+		return types.MetadataArea{}, xerrors.Errorf("failed to write metadata area header: %w", err)
+	}
+
+	mda.RawLocationDescriptors = locs
+	mda.Copies = append([]types.MetadataCopy{{
+		Offset:   offset,
+		Size:     int64(len(raw)),
+		Checksum: checksum,
+		Seqno:    m.Seqno,
+		Raw:      text,
+		Metadata: m,
+	}}, mda.Copies...)
+
+	return mda, nil
+}
+
+func nextSeqno(mda types.MetadataArea) int64 {
+	if len(mda.Copies) == 0 {
+		return 1
+	}
+	return mda.Copies[0].Seqno + 1
+}
+
+// nextRingOffset places the new copy right after the current newest one,
+// wrapping back to just past the SectorSize-byte mda_header (not to byte
+// 0 of the area) when the newest copy ends at or past the end of the
+// area. The new copy's size is not considered: a copy that starts inside
+// the area but runs past its end is split by writeRingBuffer. It does not
+// attempt to reclaim space from copies other than the one it overwrites.
+func nextRingOffset(mda types.MetadataArea, ringSize int64) int64 {
+	if len(mda.Copies) == 0 {
+		return SectorSize
+	}
+	newest := mda.Copies[0]
+	offset := newest.Offset + newest.Size
+	if offset >= ringSize {
+		offset = SectorSize + (offset - ringSize)
+	}
+	return offset
+}
+
+// setLocationSlot returns mda's raw_locn list with loc written into the
+// slot that isn't the current newest copy, double-buffering between two
+// slots the way LVM2 does rather than growing the list forever.
+func setLocationSlot(mda types.MetadataArea, loc types.RawLocationDescriptor) []types.RawLocationDescriptor {
+	if len(mda.RawLocationDescriptors) < 2 {
+		return append(append([]types.RawLocationDescriptor{}, mda.RawLocationDescriptors...), loc)
+	}
+
+	var newestOffset int64 = -1
+	if len(mda.Copies) > 0 {
+		newestOffset = mda.Copies[0].Offset
+	}
+	locs := append([]types.RawLocationDescriptor{}, mda.RawLocationDescriptors...)
+	for i, d := range locs {
+		if d.DataAreaOffset != newestOffset {
+			locs[i] = loc
+			return locs
+		}
+	}
+	locs[0] = loc
+	return locs
+}
+
+// writeRingBuffer writes data at offset — an absolute offset from
+// areaStart, exactly as raw_locn records it — within a metadata area
+// spanning ringSize bytes (mda_header.size, the whole area including its
+// SectorSize-byte header) from areaStart. It stitches two writes together
+// when the range wraps past the end of the area, since wrapped data
+// resumes at areaStart+SectorSize, right after the header, not at
+// areaStart itself.
+func writeRingBuffer(w io.WriteSeeker, areaStart, ringSize, offset int64, data []byte) error {
+	size := int64(len(data))
+	if offset+size <= ringSize {
+		if _, err := w.Seek(areaStart+offset, io.SeekStart); err != nil {
+			return err
+		}
+		_, err := w.Write(data)
+		return err
+	}
+
+	first := ringSize - offset
+	if _, err := w.Seek(areaStart+offset, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := w.Write(data[:first]); err != nil {
+		return err
+	}
+	if _, err := w.Seek(areaStart+SectorSize, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := w.Write(data[first:])
+	return err
+}
+
+// writeMetadataAreaHeader rewrites a metadata area's header sector:
+// header, the raw_locn list, a NULL-terminating descriptor, and the
+// checksum covering everything after the checksum field itself.
+func writeMetadataAreaHeader(w io.WriteSeeker, areaOffset int64, header types.MetadataAreaHeader, locs []types.RawLocationDescriptor) error {
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, header); err != nil {
+		return err
+	}
+	for _, d := range locs {
+		if err := binary.Write(&buf, binary.LittleEndian, d); err != nil {
+			return err
+		}
+	}
+	if err := binary.Write(&buf, binary.LittleEndian, types.RawLocationDescriptor{}); err != nil {
+		return err
+	}
+	if buf.Len() > SectorSize {
+		return xerrors.Errorf("metadata area header and %d raw locations exceed one sector (%d > %d bytes)", len(locs), buf.Len(), SectorSize)
+	}
+
+	sector := make([]byte, SectorSize)
+	copy(sector, buf.Bytes())
+	binary.LittleEndian.PutUint32(sector[0:4], crc32LVM(sector[4:]))
+
+	if _, err := w.Seek(areaOffset, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := w.Write(sector)
+	return err
+}